@@ -0,0 +1,109 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package endorsement
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/protos/msp"
+	"github.com/hyperledger/fabric/protos/peer"
+)
+
+// principalClassifier resolves the MSP that a principal is scoped to. It is
+// satisfied by acl.DiscoverySupport.
+type principalClassifier interface {
+	MSPOfPrincipal(principal *msp.MSPPrincipal) string
+}
+
+// Layout is the endorsement layout computed for a private collection: the
+// principals that must endorse, grouped by the MSP they were classified
+// under, with IDENTITY-classified principals that resolve to the same
+// underlying identity collapsed into a single entry.
+type Layout struct {
+	// Groups maps an MSP ID to the principals, classified under that MSP,
+	// that together satisfy the collection's member-orgs policy.
+	Groups map[string][]*msp.MSPPrincipal
+}
+
+// LayoutForCollection computes the Layout for collectionConfig: it classifies
+// every principal referenced by the collection's member-orgs policy to the
+// MSP it belongs to via classifier, groups them accordingly, and
+// deduplicates IDENTITY-classified principals within each group so that a
+// threshold-signature endorsement plugin returning the same identity for
+// multiple principals doesn't count it twice in the resulting layout.
+func LayoutForCollection(classifier principalClassifier, collectionConfig *peer.CollectionConfig) *Layout {
+	grouped := principalsFromCollectionConfig(classifier, collectionConfig)
+	layout := &Layout{Groups: make(map[string][]*msp.MSPPrincipal, len(grouped))}
+	for mspID, principals := range grouped {
+		layout.Groups[mspID] = dedupIdentityPrincipals(principals)
+	}
+	return layout
+}
+
+// principalsFromCollectionConfig extracts the set of principals referenced by
+// the endorsement policy of a private collection config, resolving ROLE,
+// ORGANIZATION_UNIT and IDENTITY classified principals alike to the MSP that
+// they belong to.
+func principalsFromCollectionConfig(classifier principalClassifier, collectionConfig *peer.CollectionConfig) map[string][]*msp.MSPPrincipal {
+	res := make(map[string][]*msp.MSPPrincipal)
+	staticConfig := collectionConfig.GetStaticCollectionConfig()
+	if staticConfig == nil || staticConfig.MemberOrgsPolicy == nil {
+		return res
+	}
+	signaturePolicy := staticConfig.MemberOrgsPolicy.GetSignaturePolicy()
+	if signaturePolicy == nil {
+		return res
+	}
+	for _, principal := range signaturePolicy.Identities {
+		mspID := classifier.MSPOfPrincipal(principal)
+		if mspID == "" {
+			continue
+		}
+		res[mspID] = append(res[mspID], principal)
+	}
+	return res
+}
+
+// comparableIdentity is a serialized identity that can be used as a map key,
+// so that multiple endorsements stemming from the same identity can be
+// collapsed into one layout entry.
+type comparableIdentity string
+
+func comparableIdentityFromPrincipal(principal *msp.MSPPrincipal) (comparableIdentity, bool) {
+	if principal == nil || principal.PrincipalClassification != msp.MSPPrincipal_IDENTITY {
+		return "", false
+	}
+	sID := &msp.SerializedIdentity{}
+	if err := proto.Unmarshal(principal.Principal, sID); err != nil {
+		return "", false
+	}
+	// NUL-delimited so that, e.g., Mspid="Org1" IdBytes="MSPalice" can't
+	// collide with Mspid="Org1MSP" IdBytes="alice": NUL can't appear
+	// unescaped in a valid MSP ID.
+	return comparableIdentity(sID.Mspid + "\x00" + string(sID.IdBytes)), true
+}
+
+// dedupIdentityPrincipals collapses principals that classify to the same
+// serialized identity, so that a threshold-signature endorsement plugin that
+// returns the same identity from multiple peers produces a single layout
+// entry rather than being double counted.
+func dedupIdentityPrincipals(principals []*msp.MSPPrincipal) []*msp.MSPPrincipal {
+	seen := make(map[comparableIdentity]struct{})
+	res := make([]*msp.MSPPrincipal, 0, len(principals))
+	for _, principal := range principals {
+		id, isIdentity := comparableIdentityFromPrincipal(principal)
+		if !isIdentity {
+			res = append(res, principal)
+			continue
+		}
+		if _, exists := seen[id]; exists {
+			continue
+		}
+		seen[id] = struct{}{}
+		res = append(res, principal)
+	}
+	return res
+}
@@ -0,0 +1,178 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package endorsement
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/msp"
+	"github.com/hyperledger/fabric/protos/peer"
+	"github.com/stretchr/testify/assert"
+)
+
+type classifierFunc func(principal *msp.MSPPrincipal) string
+
+func (f classifierFunc) MSPOfPrincipal(principal *msp.MSPPrincipal) string {
+	return f(principal)
+}
+
+func staticCollectionConfig(identities ...*msp.MSPPrincipal) *peer.CollectionConfig {
+	return &peer.CollectionConfig{
+		Payload: &peer.CollectionConfig_StaticCollectionConfig{
+			StaticCollectionConfig: &peer.StaticCollectionConfig{
+				MemberOrgsPolicy: &peer.CollectionPolicyConfig{
+					Payload: &peer.CollectionPolicyConfig_SignaturePolicy{
+						SignaturePolicy: &common.SignaturePolicyEnvelope{
+							Identities: identities,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func identityPrincipal(t *testing.T, mspID, idBytes string) *msp.MSPPrincipal {
+	t.Helper()
+	raw, err := proto.Marshal(&msp.SerializedIdentity{Mspid: mspID, IdBytes: []byte(idBytes)})
+	assert.NoError(t, err)
+	return &msp.MSPPrincipal{
+		PrincipalClassification: msp.MSPPrincipal_IDENTITY,
+		Principal:               raw,
+	}
+}
+
+func rolePrincipal() *msp.MSPPrincipal {
+	return &msp.MSPPrincipal{PrincipalClassification: msp.MSPPrincipal_ROLE}
+}
+
+func TestPrincipalsFromCollectionConfigNoStaticConfig(t *testing.T) {
+	res := principalsFromCollectionConfig(classifierFunc(func(*msp.MSPPrincipal) string {
+		t.Fatal("classifier should not be consulted")
+		return ""
+	}), &peer.CollectionConfig{})
+	assert.Empty(t, res)
+}
+
+func TestPrincipalsFromCollectionConfigNoSignaturePolicy(t *testing.T) {
+	collectionConfig := &peer.CollectionConfig{
+		Payload: &peer.CollectionConfig_StaticCollectionConfig{
+			StaticCollectionConfig: &peer.StaticCollectionConfig{},
+		},
+	}
+	res := principalsFromCollectionConfig(classifierFunc(func(*msp.MSPPrincipal) string {
+		t.Fatal("classifier should not be consulted")
+		return ""
+	}), collectionConfig)
+	assert.Empty(t, res)
+}
+
+func TestPrincipalsFromCollectionConfigGroupsByMSP(t *testing.T) {
+	org1A := rolePrincipal()
+	org1B := rolePrincipal()
+	unclassified := rolePrincipal()
+	collectionConfig := staticCollectionConfig(org1A, org1B, unclassified)
+
+	classifier := classifierFunc(func(principal *msp.MSPPrincipal) string {
+		switch principal {
+		case org1A, org1B:
+			return "Org1MSP"
+		default:
+			return ""
+		}
+	})
+
+	res := principalsFromCollectionConfig(classifier, collectionConfig)
+	assert.Equal(t, map[string][]*msp.MSPPrincipal{
+		"Org1MSP": {org1A, org1B},
+	}, res)
+}
+
+func TestComparableIdentityFromPrincipalNilPrincipal(t *testing.T) {
+	_, ok := comparableIdentityFromPrincipal(nil)
+	assert.False(t, ok)
+}
+
+func TestComparableIdentityFromPrincipalNotIdentityClassified(t *testing.T) {
+	_, ok := comparableIdentityFromPrincipal(rolePrincipal())
+	assert.False(t, ok)
+}
+
+func TestComparableIdentityFromPrincipalUnmarshalFailure(t *testing.T) {
+	_, ok := comparableIdentityFromPrincipal(&msp.MSPPrincipal{
+		PrincipalClassification: msp.MSPPrincipal_IDENTITY,
+		Principal:               []byte("not a serialized identity"),
+	})
+	assert.False(t, ok)
+}
+
+func TestComparableIdentityFromPrincipalSuccess(t *testing.T) {
+	id, ok := comparableIdentityFromPrincipal(identityPrincipal(t, "Org1MSP", "identity-bytes"))
+	assert.True(t, ok)
+	assert.Equal(t, comparableIdentity("Org1MSP\x00identity-bytes"), id)
+}
+
+func TestComparableIdentityFromPrincipalDoesNotCollideAcrossTheDelimiter(t *testing.T) {
+	// Without a delimiter between Mspid and IdBytes, these two would both
+	// produce the key "Org1MSPalice".
+	a, ok := comparableIdentityFromPrincipal(identityPrincipal(t, "Org1", "MSPalice"))
+	assert.True(t, ok)
+	b, ok := comparableIdentityFromPrincipal(identityPrincipal(t, "Org1MSP", "alice"))
+	assert.True(t, ok)
+	assert.NotEqual(t, a, b)
+}
+
+func TestDedupIdentityPrincipalsCollapsesDuplicates(t *testing.T) {
+	first := identityPrincipal(t, "Org1MSP", "alice")
+	duplicate := identityPrincipal(t, "Org1MSP", "alice")
+	distinct := identityPrincipal(t, "Org1MSP", "bob")
+	role := rolePrincipal()
+
+	res := dedupIdentityPrincipals([]*msp.MSPPrincipal{first, role, duplicate, distinct})
+	assert.Equal(t, []*msp.MSPPrincipal{first, role, distinct}, res)
+}
+
+func TestDedupIdentityPrincipalsPreservesNonIdentityPrincipals(t *testing.T) {
+	roleA := rolePrincipal()
+	roleB := rolePrincipal()
+
+	res := dedupIdentityPrincipals([]*msp.MSPPrincipal{roleA, roleB})
+	assert.Equal(t, []*msp.MSPPrincipal{roleA, roleB}, res)
+}
+
+// TestLayoutForCollectionGroupsAndDedups verifies that LayoutForCollection
+// actually wires principalsFromCollectionConfig and dedupIdentityPrincipals
+// together: it groups a collection's principals by MSP and collapses
+// duplicate identities within each group, rather than leaving the two
+// helpers as dead code reachable only from their own unit tests.
+func TestLayoutForCollectionGroupsAndDedups(t *testing.T) {
+	aliceA := identityPrincipal(t, "Org1MSP", "alice")
+	aliceB := identityPrincipal(t, "Org1MSP", "alice")
+	bob := identityPrincipal(t, "Org1MSP", "bob")
+	org2Role := rolePrincipal()
+	unclassified := rolePrincipal()
+
+	collectionConfig := staticCollectionConfig(aliceA, aliceB, bob, org2Role, unclassified)
+	classifier := classifierFunc(func(principal *msp.MSPPrincipal) string {
+		switch principal {
+		case aliceA, aliceB, bob:
+			return "Org1MSP"
+		case org2Role:
+			return "Org2MSP"
+		default:
+			return ""
+		}
+	})
+
+	layout := LayoutForCollection(classifier, collectionConfig)
+	assert.Equal(t, map[string][]*msp.MSPPrincipal{
+		"Org1MSP": {aliceA, bob},
+		"Org2MSP": {org2Role},
+	}, layout.Groups)
+}
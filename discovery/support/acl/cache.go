@@ -0,0 +1,156 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package acl
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/channelconfig"
+)
+
+// SequenceSource optionally supplements a ChannelConfigGetter with a cheap
+// way to read a channel's current config sequence number, without paying
+// the cost of resolving and deserializing the full channelconfig.Resources
+// that GetChannelConfig does. When the ChannelConfigGetter passed to
+// NewCachingDiscoverySupport also implements SequenceSource,
+// CachingDiscoverySupport uses it to detect a bumped sequence between
+// refreshes and re-fetches immediately, instead of waiting out the full
+// refresh interval.
+type SequenceSource interface {
+	// ConfigSequence returns the current configuration sequence number for
+	// cid, or false if it isn't known.
+	ConfigSequence(cid string) (uint64, bool)
+}
+
+// channelConfigReference is a lazily computed, self-refreshing view of a
+// channel's configuration. The underlying channelconfig.Resources is
+// re-fetched the first time it is accessed after the refresh interval has
+// elapsed, after sequence reports a config sequence different from the one
+// observed at the last fetch, or after invalidate has been called.
+//
+// An earlier version of this bump check compared the cached snapshot's own
+// Sequence() against itself and could never actually observe a bump.
+// sequence must be backed by a source that is independent of the cached
+// resources, such as SequenceSource, for the check to mean anything.
+type channelConfigReference struct {
+	fetch    func() channelconfig.Resources
+	refresh  time.Duration
+	sequence func() (uint64, bool)
+
+	mutex        sync.Mutex
+	fetched      bool
+	lastFetched  time.Time
+	lastSequence uint64
+	resources    channelconfig.Resources
+}
+
+func newChannelConfigReference(fetch func() channelconfig.Resources, refresh time.Duration, sequence func() (uint64, bool)) *channelConfigReference {
+	return &channelConfigReference{fetch: fetch, refresh: refresh, sequence: sequence}
+}
+
+func (r *channelConfigReference) get() channelconfig.Resources {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	stale := !r.fetched || time.Since(r.lastFetched) >= r.refresh
+	if !stale && r.sequence != nil {
+		if seq, ok := r.sequence(); ok && seq != r.lastSequence {
+			stale = true
+		}
+	}
+	if stale {
+		r.resources = r.fetch()
+		r.lastFetched = time.Now()
+		r.fetched = true
+		if r.sequence != nil {
+			if seq, ok := r.sequence(); ok {
+				r.lastSequence = seq
+			}
+		}
+	}
+	return r.resources
+}
+
+// invalidate discards the cached resources, forcing the next call to get to
+// re-fetch them.
+func (r *channelConfigReference) invalidate() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.fetched = false
+}
+
+// CachingDiscoverySupport wraps a DiscoverySupport so that the
+// channelconfig.Resources it resolves are cached, keyed by channel name,
+// instead of being looked up on every call. It replaces the embedded
+// DiscoverySupport's resolveConfig with a caching variant, so every method
+// that goes through resolveConfig - ConfigSequence, SatisfiesPrincipal and
+// the batched SatisfiesPrincipalSet/SatisfiesPrincipalSetForIdentities alike
+// - is covered by the cache without needing its own override. Each entry
+// refreshes itself once the configured interval elapses, as soon as it
+// observes a bumped config sequence via SequenceSource (if chConfig
+// implements it), or when Invalidate is called, which is meant to be wired
+// up to config-update notifications.
+type CachingDiscoverySupport struct {
+	*DiscoverySupport
+	refresh   time.Duration
+	sequences SequenceSource
+
+	mutex sync.Mutex
+	cache map[string]*channelConfigReference
+}
+
+// NewCachingDiscoverySupport creates a new CachingDiscoverySupport that
+// caches channel configuration for up to refresh before re-fetching it. If
+// chConfig also implements SequenceSource, a bumped sequence triggers a
+// re-fetch immediately, without waiting out refresh.
+func NewCachingDiscoverySupport(v Verifier, e Evaluator, chConfig ChannelConfigGetter, refresh time.Duration, opts ...Option) *CachingDiscoverySupport {
+	base := NewDiscoverySupport(v, e, chConfig, opts...)
+	underlyingResolve := base.resolveConfig
+	sequences, _ := chConfig.(SequenceSource)
+
+	s := &CachingDiscoverySupport{
+		DiscoverySupport: base,
+		refresh:          refresh,
+		sequences:        sequences,
+		cache:            make(map[string]*channelConfigReference),
+	}
+	base.resolveConfig = func(channel string) channelconfig.Resources {
+		return s.refFor(channel, underlyingResolve).get()
+	}
+	return s
+}
+
+func (s *CachingDiscoverySupport) refFor(channel string, fetch func(string) channelconfig.Resources) *channelConfigReference {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	ref, exists := s.cache[channel]
+	if !exists {
+		var sequence func() (uint64, bool)
+		if s.sequences != nil {
+			sequence = func() (uint64, bool) { return s.sequences.ConfigSequence(channel) }
+		}
+		ref = newChannelConfigReference(func() channelconfig.Resources {
+			return fetch(channel)
+		}, s.refresh, sequence)
+		s.cache[channel] = ref
+	}
+	return ref
+}
+
+// Invalidate discards the cached configuration for the given channel so that
+// the next query against it re-fetches the configuration. It is meant to be
+// wired up to config-update notifications.
+func (s *CachingDiscoverySupport) Invalidate(channel string) {
+	s.mutex.Lock()
+	ref, exists := s.cache[channel]
+	s.mutex.Unlock()
+	if exists {
+		ref.invalidate()
+	}
+}
@@ -0,0 +1,166 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package acl_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/hyperledger/fabric/discovery/support/acl"
+	"github.com/hyperledger/fabric/discovery/support/mocks"
+	"github.com/hyperledger/fabric/protos/msp"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubChanConfigWithSequence is a hand-rolled ChannelConfigGetter double that
+// also implements acl.SequenceSource, so CachingDiscoverySupport can probe
+// the channel's sequence without re-resolving its channelconfig.Resources.
+type stubChanConfigWithSequence struct {
+	resources channelconfig.Resources
+	sequence  uint64
+	fetches   int
+}
+
+func (s *stubChanConfigWithSequence) GetChannelConfig(cid string) channelconfig.Resources {
+	s.fetches++
+	return s.resources
+}
+
+func (s *stubChanConfigWithSequence) ConfigSequence(cid string) (uint64, bool) {
+	return s.sequence, true
+}
+
+func TestCachingDiscoverySupportConfigSequenceCaches(t *testing.T) {
+	chConfig := &mocks.ChanConfig{}
+	r := &mocks.Resources{}
+	v := &mocks.ConfigtxValidator{}
+	chConfig.GetChannelConfigReturns(r)
+	r.ConfigtxValidatorReturns(v)
+	v.SequenceReturns(100)
+
+	sup := acl.NewCachingDiscoverySupport(&mocks.Verifier{}, &mocks.Evaluator{}, chConfig, time.Hour)
+	assert.Equal(t, uint64(100), sup.ConfigSequence("mychannel"))
+	assert.Equal(t, uint64(100), sup.ConfigSequence("mychannel"))
+	// The channel config should only have been fetched once, since the
+	// refresh interval hasn't elapsed.
+	assert.Equal(t, 1, chConfig.GetChannelConfigCallCount())
+}
+
+func TestCachingDiscoverySupportRefreshesAfterInterval(t *testing.T) {
+	chConfig := &mocks.ChanConfig{}
+	r := &mocks.Resources{}
+	v := &mocks.ConfigtxValidator{}
+	chConfig.GetChannelConfigReturns(r)
+	r.ConfigtxValidatorReturns(v)
+	v.SequenceReturnsOnCall(0, 100)
+	v.SequenceReturnsOnCall(1, 101)
+
+	sup := acl.NewCachingDiscoverySupport(&mocks.Verifier{}, &mocks.Evaluator{}, chConfig, time.Nanosecond)
+	assert.Equal(t, uint64(100), sup.ConfigSequence("mychannel"))
+	time.Sleep(time.Millisecond)
+	assert.Equal(t, uint64(101), sup.ConfigSequence("mychannel"))
+	assert.Equal(t, 2, chConfig.GetChannelConfigCallCount())
+}
+
+func TestCachingDiscoverySupportRefreshesOnSequenceBump(t *testing.T) {
+	r := &mocks.Resources{}
+	v := &mocks.ConfigtxValidator{}
+	r.ConfigtxValidatorReturns(v)
+	v.SequenceReturnsOnCall(0, 100)
+	v.SequenceReturnsOnCall(1, 101)
+
+	chConfig := &stubChanConfigWithSequence{resources: r, sequence: 100}
+
+	sup := acl.NewCachingDiscoverySupport(&mocks.Verifier{}, &mocks.Evaluator{}, chConfig, time.Hour)
+	assert.Equal(t, uint64(100), sup.ConfigSequence("mychannel"))
+	assert.Equal(t, 1, chConfig.fetches)
+
+	// A bumped sequence, observed via the cheap SequenceSource probe, should
+	// trigger a re-fetch well before the hour-long refresh interval elapses.
+	chConfig.sequence = 101
+	assert.Equal(t, uint64(101), sup.ConfigSequence("mychannel"))
+	assert.Equal(t, 2, chConfig.fetches)
+}
+
+func TestCachingDiscoverySupportInvalidate(t *testing.T) {
+	chConfig := &mocks.ChanConfig{}
+	r := &mocks.Resources{}
+	v := &mocks.ConfigtxValidator{}
+	chConfig.GetChannelConfigReturns(r)
+	r.ConfigtxValidatorReturns(v)
+	v.SequenceReturns(100)
+
+	sup := acl.NewCachingDiscoverySupport(&mocks.Verifier{}, &mocks.Evaluator{}, chConfig, time.Hour)
+	assert.Equal(t, uint64(100), sup.ConfigSequence("mychannel"))
+	sup.Invalidate("mychannel")
+	assert.Equal(t, uint64(100), sup.ConfigSequence("mychannel"))
+	assert.Equal(t, 2, chConfig.GetChannelConfigCallCount())
+}
+
+func TestCachingDiscoverySupportConfigSequencePanics(t *testing.T) {
+	chConfig := &mocks.ChanConfig{}
+	sup := acl.NewCachingDiscoverySupport(&mocks.Verifier{}, &mocks.Evaluator{}, chConfig, time.Hour)
+	assert.Panics(t, func() {
+		sup.ConfigSequence("mychannel")
+	})
+}
+
+func TestCachingDiscoverySupportSatisfiesPrincipal(t *testing.T) {
+	chConfig := &mocks.ChanConfig{}
+	resources := &mocks.Resources{}
+	mgr := &mocks.MSPManager{}
+	id := &mocks.Identity{}
+	chConfig.GetChannelConfigReturns(resources)
+	resources.MSPManagerReturns(mgr)
+	mgr.DeserializeIdentityReturns(id, nil)
+	id.SatisfiesPrincipalReturns(nil)
+
+	sup := acl.NewCachingDiscoverySupport(&mocks.Verifier{}, &mocks.Evaluator{}, chConfig, time.Hour)
+	assert.NoError(t, sup.SatisfiesPrincipal("mychannel", nil, nil))
+	assert.Equal(t, 1, chConfig.GetChannelConfigCallCount())
+}
+
+func TestCachingDiscoverySupportChannelDoesNotExist(t *testing.T) {
+	chConfig := &mocks.ChanConfig{}
+	chConfig.GetChannelConfigReturns(nil)
+
+	sup := acl.NewCachingDiscoverySupport(&mocks.Verifier{}, &mocks.Evaluator{}, chConfig, time.Hour)
+	err := sup.SatisfiesPrincipal("mychannel", nil, nil)
+	assert.EqualError(t, err, "channel mychannel doesn't exist")
+}
+
+// TestCachingDiscoverySupportSatisfiesPrincipalSetUsesCache verifies that
+// the batched SatisfiesPrincipalSet/SatisfiesPrincipalSetForIdentities hot
+// path - inherited from the embedded DiscoverySupport - goes through the
+// same cached channel config resolution as SatisfiesPrincipal and
+// ConfigSequence do, instead of bypassing the cache.
+func TestCachingDiscoverySupportSatisfiesPrincipalSetUsesCache(t *testing.T) {
+	chConfig := &mocks.ChanConfig{}
+	resources := &mocks.Resources{}
+	mgr := &mocks.MSPManager{}
+	id := &mocks.Identity{}
+	chConfig.GetChannelConfigReturns(resources)
+	resources.MSPManagerReturns(mgr)
+	mgr.DeserializeIdentityReturns(id, nil)
+	id.SatisfiesPrincipalReturns(nil)
+
+	sup := acl.NewCachingDiscoverySupport(&mocks.Verifier{}, &mocks.Evaluator{}, chConfig, time.Hour)
+	principals := []*msp.MSPPrincipal{{}, {}}
+
+	errs := sup.SatisfiesPrincipalSet("mychannel", nil, principals)
+	assert.Len(t, errs, 2)
+	assert.NoError(t, errs[0])
+	assert.NoError(t, errs[1])
+
+	errs = sup.SatisfiesPrincipalSetForIdentities("mychannel", [][]byte{nil, nil}, principals)
+	assert.Len(t, errs, 2)
+
+	// The first batched call should have fetched the channel config and the
+	// second should have resolved it from the cache rather than re-fetching it.
+	assert.Equal(t, 1, chConfig.GetChannelConfigCallCount())
+}
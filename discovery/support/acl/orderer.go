@@ -0,0 +1,48 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package acl
+
+import (
+	"github.com/hyperledger/fabric/common/channelconfig"
+)
+
+// OrdererConfigSource retrieves the latest channel configuration known to an
+// orderer, for channels that the peer has not necessarily joined yet.
+type OrdererConfigSource interface {
+	// GetOrdererChannelConfig retrieves the latest configuration block for
+	// cid from an orderer and returns the channelconfig.Resources it
+	// describes.
+	GetOrdererChannelConfig(cid string) (channelconfig.Resources, error)
+}
+
+// Option configures optional behavior of a DiscoverySupport.
+type Option func(*DiscoverySupport)
+
+// WithOrdererConfigSource configures the DiscoverySupport to fall back to
+// src whenever a channel's configuration cannot be found locally, so that a
+// peer that has not yet joined a channel can still answer discovery queries
+// against it.
+func WithOrdererConfigSource(src OrdererConfigSource) Option {
+	return func(s *DiscoverySupport) {
+		s.ordererConfigSource = src
+	}
+}
+
+// channelConfigFromOrderer falls back to the configured OrdererConfigSource
+// to resolve a channel's configuration. It returns nil if no
+// OrdererConfigSource was configured, or if the orderer could not produce
+// the configuration either.
+func (s *DiscoverySupport) channelConfigFromOrderer(channel string) channelconfig.Resources {
+	if s.ordererConfigSource == nil {
+		return nil
+	}
+	chConf, err := s.ordererConfigSource.GetOrdererChannelConfig(channel)
+	if err != nil {
+		return nil
+	}
+	return chConf
+}
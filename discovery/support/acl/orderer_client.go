@@ -0,0 +1,264 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package acl
+
+import (
+	"context"
+	"crypto/tls"
+	"time"
+
+	"github.com/hyperledger/fabric/bccsp/factory"
+	"github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/hyperledger/fabric/common/configtx"
+	"github.com/hyperledger/fabric/common/crypto"
+	"github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Signer signs a message on behalf of the peer's local identity and
+// serializes that identity, so that requests sent to an orderer (such as a
+// deliver seek) can carry a valid signature header. msp.SigningIdentity
+// satisfies this interface.
+type Signer interface {
+	Sign(msg []byte) ([]byte, error)
+	Serialize() ([]byte, error)
+}
+
+// OrdererConnectionConfig configures how a grpcOrdererConfigSource reaches an
+// orderer in order to fetch a channel's latest configuration block.
+type OrdererConnectionConfig struct {
+	// Addresses are the orderer endpoints to try, in the order given.
+	Addresses []string
+	// Signer signs the deliver seek requests sent to the orderer. It is
+	// required, since orderers enforce the channel Readers policy on
+	// deliver requests and reject unsigned envelopes.
+	Signer Signer
+	// TLSConfig configures the TLS client used to dial the orderer. A nil
+	// value dials the orderer in plaintext.
+	TLSConfig *tls.Config
+	// DialTimeout bounds how long a single connection attempt may take.
+	DialTimeout time.Duration
+	// RetryInterval is the initial delay between attempts across the
+	// configured addresses. It doubles after every failed round, up to
+	// MaxRetryInterval.
+	RetryInterval time.Duration
+	// MaxRetryInterval caps the backoff delay between attempts.
+	MaxRetryInterval time.Duration
+	// MaxRetries is the number of further rounds through Addresses that are
+	// attempted after the first round fails, before giving up, so at most
+	// MaxRetries+1 rounds are made. A zero value means a single round.
+	MaxRetries int
+}
+
+// grpcOrdererConfigSource is an OrdererConfigSource that retrieves the
+// latest configuration block for a channel over a gRPC connection to an
+// orderer's deliver service, with retry and backoff across the configured
+// orderer endpoints.
+type grpcOrdererConfigSource struct {
+	OrdererConnectionConfig
+}
+
+// NewOrdererConfigSource creates an OrdererConfigSource backed by a live
+// connection to one of the orderers described by config.
+func NewOrdererConfigSource(config OrdererConnectionConfig) OrdererConfigSource {
+	return &grpcOrdererConfigSource{OrdererConnectionConfig: config}
+}
+
+// GetOrdererChannelConfig retrieves the latest configuration block for cid
+// from an orderer and parses it into channelconfig.Resources.
+func (s *grpcOrdererConfigSource) GetOrdererChannelConfig(cid string) (channelconfig.Resources, error) {
+	var block *common.Block
+	var lastErr error
+
+	interval := s.RetryInterval
+	if interval == 0 {
+		interval = 500 * time.Millisecond
+	}
+	maxInterval := s.MaxRetryInterval
+	if maxInterval == 0 {
+		maxInterval = 5 * time.Second
+	}
+
+	for attempt := 0; attempt <= s.MaxRetries; attempt++ {
+		for _, address := range s.Addresses {
+			b, err := s.fetchConfigBlock(address, cid)
+			if err == nil {
+				block = b
+				break
+			}
+			lastErr = err
+		}
+		if block != nil {
+			break
+		}
+		if attempt < s.MaxRetries {
+			time.Sleep(interval)
+			if interval *= 2; interval > maxInterval {
+				interval = maxInterval
+			}
+		}
+	}
+	if block == nil {
+		return nil, errors.Wrapf(lastErr, "failed retrieving config block for channel %s from orderer", cid)
+	}
+
+	envelope, err := utils.ExtractEnvelope(block, 0)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed extracting envelope from config block")
+	}
+	payload, err := utils.UnmarshalPayload(envelope.Payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed unmarshaling envelope payload")
+	}
+	configEnvelope, err := configtx.UnmarshalConfigEnvelope(payload.Data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed unmarshaling config envelope")
+	}
+	bundle, err := channelconfig.NewBundle(cid, configEnvelope.Config, factory.GetDefault())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed constructing channel config bundle")
+	}
+	return bundle, nil
+}
+
+// fetchConfigBlock retrieves the actual configuration block for cid from
+// address. The newest block on a channel is ordinarily a transaction block,
+// not a config block, so this first seeks the newest block to read its
+// LAST_CONFIG metadata, and then seeks that specific block.
+func (s *grpcOrdererConfigSource) fetchConfigBlock(address string, cid string) (*common.Block, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.dialTimeout())
+	defer cancel()
+
+	conn, err := grpc.DialContext(ctx, address, s.dialOpts()...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed connecting to orderer %s", address)
+	}
+	defer conn.Close()
+
+	broadcastClient := ab.NewAtomicBroadcastClient(conn)
+
+	newest, err := s.seekBlock(ctx, broadcastClient, address, cid, seekNewest())
+	if err != nil {
+		return nil, err
+	}
+
+	lastConfigIndex, err := utils.GetLastConfigIndexFromBlock(newest)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed reading last config index from newest block of channel %s", cid)
+	}
+	if newest.Header.Number == lastConfigIndex {
+		return newest, nil
+	}
+
+	configBlock, err := s.seekBlock(ctx, broadcastClient, address, cid, seekSpecified(lastConfigIndex))
+	if err != nil {
+		return nil, err
+	}
+	return configBlock, nil
+}
+
+func (s *grpcOrdererConfigSource) seekBlock(ctx context.Context, broadcastClient ab.AtomicBroadcastClient, address, cid string, seekInfo *ab.SeekInfo) (*common.Block, error) {
+	envelope, err := s.signedSeekEnvelope(cid, seekInfo)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed signing deliver seek request")
+	}
+
+	client, err := broadcastClient.Deliver(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed creating deliver client to orderer %s", address)
+	}
+	defer client.CloseSend()
+
+	if err := client.Send(envelope); err != nil {
+		return nil, errors.Wrapf(err, "failed requesting block from orderer %s", address)
+	}
+
+	resp, err := client.Recv()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed receiving block from orderer %s", address)
+	}
+	block := resp.GetBlock()
+	if block == nil {
+		return nil, errors.Errorf("orderer %s did not return a block for channel %s", address, cid)
+	}
+	return block, nil
+}
+
+// signedSeekEnvelope builds a deliver seek request for the given channel and
+// signs it with s.Signer, since orderers enforce the channel Readers policy
+// on deliver requests.
+func (s *grpcOrdererConfigSource) signedSeekEnvelope(channel string, seekInfo *ab.SeekInfo) (*common.Envelope, error) {
+	if s.Signer == nil {
+		return nil, errors.New("no signer configured for orderer deliver requests")
+	}
+	return utils.CreateSignedEnvelope(common.HeaderType_DELIVER_SEEK_INFO, channel, localSigner{s.Signer}, seekInfo, int32(0), uint64(0))
+}
+
+// localSigner adapts a Signer to crypto.LocalSigner, the interface that
+// utils.CreateSignedEnvelope actually requires, by deriving a signature
+// header from the signer's serialized identity and a fresh nonce.
+type localSigner struct {
+	Signer
+}
+
+// NewSignatureHeader builds the signature header that
+// utils.CreateSignedEnvelope embeds alongside the payload it signs.
+func (s localSigner) NewSignatureHeader() (*common.SignatureHeader, error) {
+	creator, err := s.Serialize()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed serializing signer identity")
+	}
+	nonce, err := crypto.GetRandomNonce()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed generating nonce")
+	}
+	return &common.SignatureHeader{
+		Creator: creator,
+		Nonce:   nonce,
+	}, nil
+}
+
+// seekNewest builds a seek position selecting only the most recently
+// committed block on a channel.
+func seekNewest() *ab.SeekInfo {
+	return &ab.SeekInfo{
+		Start:    &ab.SeekPosition{Type: &ab.SeekPosition_Newest{Newest: &ab.SeekNewest{}}},
+		Stop:     &ab.SeekPosition{Type: &ab.SeekPosition_Newest{Newest: &ab.SeekNewest{}}},
+		Behavior: ab.SeekInfo_BLOCK_UNTIL_READY,
+	}
+}
+
+// seekSpecified builds a seek position selecting exactly the block at num.
+func seekSpecified(num uint64) *ab.SeekInfo {
+	position := &ab.SeekPosition{Type: &ab.SeekPosition_Specified{Specified: &ab.SeekSpecified{Number: num}}}
+	return &ab.SeekInfo{
+		Start:    position,
+		Stop:     position,
+		Behavior: ab.SeekInfo_BLOCK_UNTIL_READY,
+	}
+}
+
+func (s *grpcOrdererConfigSource) dialTimeout() time.Duration {
+	if s.DialTimeout == 0 {
+		return 5 * time.Second
+	}
+	return s.DialTimeout
+}
+
+func (s *grpcOrdererConfigSource) dialOpts() []grpc.DialOption {
+	if s.TLSConfig == nil {
+		return []grpc.DialOption{grpc.WithInsecure(), grpc.WithBlock()}
+	}
+	return []grpc.DialOption{
+		grpc.WithTransportCredentials(credentials.NewTLS(s.TLSConfig)),
+		grpc.WithBlock(),
+	}
+}
@@ -0,0 +1,184 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package acl_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/discovery/support/acl"
+	"github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// stubSigner is a hand-rolled Signer double that produces deterministic,
+// non-empty output so the deliver envelopes it signs carry a creator and a
+// signature.
+type stubSigner struct{}
+
+func (stubSigner) Sign(msg []byte) ([]byte, error) { return []byte("signature"), nil }
+func (stubSigner) Serialize() ([]byte, error)      { return []byte("creator"), nil }
+
+// fakeDeliverServer is a minimal ab.AtomicBroadcastServer that answers a
+// single deliver seek request per RPC, based on the seek position it was
+// sent: SeekNewest returns newestBlock, SeekSpecified returns the matching
+// entry in blocksByNumber. Setting fail makes every RPC fail, to exercise
+// the retry/backoff path.
+type fakeDeliverServer struct {
+	newestBlock    *common.Block
+	blocksByNumber map[uint64]*common.Block
+	fail           bool
+}
+
+func (f *fakeDeliverServer) Broadcast(ab.AtomicBroadcast_BroadcastServer) error {
+	return errors.New("not implemented")
+}
+
+func (f *fakeDeliverServer) Deliver(srv ab.AtomicBroadcast_DeliverServer) error {
+	if f.fail {
+		return errors.New("simulated orderer failure")
+	}
+
+	envelope, err := srv.Recv()
+	if err != nil {
+		return err
+	}
+	payload, err := utils.UnmarshalPayload(envelope.Payload)
+	if err != nil {
+		return err
+	}
+	seekInfo := &ab.SeekInfo{}
+	if err := proto.Unmarshal(payload.Data, seekInfo); err != nil {
+		return err
+	}
+
+	var block *common.Block
+	switch start := seekInfo.Start.Type.(type) {
+	case *ab.SeekPosition_Newest:
+		block = f.newestBlock
+	case *ab.SeekPosition_Specified:
+		block = f.blocksByNumber[start.Specified.Number]
+	}
+	if block == nil {
+		return errors.New("no matching block for seek request")
+	}
+	return srv.Send(&ab.DeliverResponse{Type: &ab.DeliverResponse_Block{Block: block}})
+}
+
+func startFakeOrderer(t *testing.T, server *fakeDeliverServer) (address string, stop func()) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	grpcServer := grpc.NewServer()
+	ab.RegisterAtomicBroadcastServer(grpcServer, server)
+	go grpcServer.Serve(lis)
+
+	return lis.Addr().String(), grpcServer.Stop
+}
+
+func newConfigBlock(t *testing.T, number uint64) *common.Block {
+	configEnvelope := &common.ConfigEnvelope{Config: &common.Config{ChannelGroup: &common.ConfigGroup{}}}
+	payload := &common.Payload{
+		Header: &common.Header{
+			ChannelHeader: utils.MarshalOrPanic(&common.ChannelHeader{Type: int32(common.HeaderType_CONFIG), ChannelId: "mychannel"}),
+		},
+		Data: utils.MarshalOrPanic(configEnvelope),
+	}
+	envelope := &common.Envelope{Payload: utils.MarshalOrPanic(payload)}
+
+	return &common.Block{
+		Header: &common.BlockHeader{Number: number},
+		Data:   &common.BlockData{Data: [][]byte{utils.MarshalOrPanic(envelope)}},
+		Metadata: &common.BlockMetadata{
+			Metadata: blockMetadataWithLastConfig(number),
+		},
+	}
+}
+
+func newTransactionBlock(t *testing.T, number, lastConfigIndex uint64) *common.Block {
+	return &common.Block{
+		Header:   &common.BlockHeader{Number: number},
+		Data:     &common.BlockData{Data: [][]byte{}},
+		Metadata: &common.BlockMetadata{Metadata: blockMetadataWithLastConfig(lastConfigIndex)},
+	}
+}
+
+func blockMetadataWithLastConfig(index uint64) [][]byte {
+	metadata := make([][]byte, common.BlockMetadataIndex_LAST_CONFIG+1)
+	metadata[common.BlockMetadataIndex_LAST_CONFIG] = utils.MarshalOrPanic(&common.Metadata{
+		Value: utils.MarshalOrPanic(&common.LastConfig{Index: index}),
+	})
+	return metadata
+}
+
+func TestGetOrdererChannelConfigFetchesConfigBlockNotNewest(t *testing.T) {
+	configBlock := newConfigBlock(t, 3)
+	newestBlock := newTransactionBlock(t, 5, 3)
+
+	address, stop := startFakeOrderer(t, &fakeDeliverServer{
+		newestBlock:    newestBlock,
+		blocksByNumber: map[uint64]*common.Block{3: configBlock},
+	})
+	defer stop()
+
+	src := acl.NewOrdererConfigSource(acl.OrdererConnectionConfig{
+		Addresses:   []string{address},
+		Signer:      stubSigner{},
+		DialTimeout: 2 * time.Second,
+	})
+
+	resources, err := src.GetOrdererChannelConfig("mychannel")
+	require.NoError(t, err)
+	require.NotNil(t, resources)
+}
+
+func TestGetOrdererChannelConfigRetriesAcrossAddresses(t *testing.T) {
+	configBlock := newConfigBlock(t, 1)
+	newestBlock := newTransactionBlock(t, 1, 1)
+
+	failingAddress, stopFailing := startFakeOrderer(t, &fakeDeliverServer{fail: true})
+	defer stopFailing()
+	workingAddress, stopWorking := startFakeOrderer(t, &fakeDeliverServer{
+		newestBlock:    newestBlock,
+		blocksByNumber: map[uint64]*common.Block{1: configBlock},
+	})
+	defer stopWorking()
+
+	src := acl.NewOrdererConfigSource(acl.OrdererConnectionConfig{
+		Addresses:     []string{failingAddress, workingAddress},
+		Signer:        stubSigner{},
+		DialTimeout:   2 * time.Second,
+		RetryInterval: time.Millisecond,
+	})
+
+	resources, err := src.GetOrdererChannelConfig("mychannel")
+	require.NoError(t, err)
+	require.NotNil(t, resources)
+}
+
+func TestGetOrdererChannelConfigFailsAfterRetries(t *testing.T) {
+	address, stop := startFakeOrderer(t, &fakeDeliverServer{fail: true})
+	defer stop()
+
+	src := acl.NewOrdererConfigSource(acl.OrdererConnectionConfig{
+		Addresses:        []string{address},
+		Signer:           stubSigner{},
+		DialTimeout:      2 * time.Second,
+		RetryInterval:    time.Millisecond,
+		MaxRetryInterval: 2 * time.Millisecond,
+		MaxRetries:       2,
+	})
+
+	_, err := src.GetOrdererChannelConfig("mychannel")
+	require.Error(t, err)
+}
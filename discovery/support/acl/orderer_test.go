@@ -0,0 +1,66 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package acl_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/hyperledger/fabric/discovery/support/acl"
+	"github.com/hyperledger/fabric/discovery/support/mocks"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubOrdererConfigSource is a hand-rolled OrdererConfigSource double for
+// exercising the orderer fallback path.
+type stubOrdererConfigSource struct {
+	resources channelconfig.Resources
+	err       error
+}
+
+func (s *stubOrdererConfigSource) GetOrdererChannelConfig(cid string) (channelconfig.Resources, error) {
+	return s.resources, s.err
+}
+
+func TestSatisfiesPrincipalOrdererFallback(t *testing.T) {
+	chConfig := &mocks.ChanConfig{}
+	chConfig.GetChannelConfigReturns(nil)
+
+	resources := &mocks.Resources{}
+	mgr := &mocks.MSPManager{}
+	id := &mocks.Identity{}
+	resources.MSPManagerReturns(mgr)
+	mgr.DeserializeIdentityReturns(id, nil)
+	id.SatisfiesPrincipalReturns(nil)
+
+	ordererSource := &stubOrdererConfigSource{resources: resources}
+	sup := acl.NewDiscoverySupport(&mocks.Verifier{}, &mocks.Evaluator{}, chConfig, acl.WithOrdererConfigSource(ordererSource))
+
+	err := sup.SatisfiesPrincipal("mychannel", nil, nil)
+	assert.NoError(t, err)
+}
+
+func TestSatisfiesPrincipalOrdererFallbackFailure(t *testing.T) {
+	chConfig := &mocks.ChanConfig{}
+	chConfig.GetChannelConfigReturns(nil)
+
+	ordererSource := &stubOrdererConfigSource{err: errors.New("orderer unreachable")}
+	sup := acl.NewDiscoverySupport(&mocks.Verifier{}, &mocks.Evaluator{}, chConfig, acl.WithOrdererConfigSource(ordererSource))
+
+	err := sup.SatisfiesPrincipal("mychannel", nil, nil)
+	assert.EqualError(t, err, "channel mychannel doesn't exist")
+}
+
+func TestSatisfiesPrincipalNoOrdererConfigSource(t *testing.T) {
+	chConfig := &mocks.ChanConfig{}
+	chConfig.GetChannelConfigReturns(nil)
+
+	sup := acl.NewDiscoverySupport(&mocks.Verifier{}, &mocks.Evaluator{}, chConfig)
+	err := sup.SatisfiesPrincipal("mychannel", nil, nil)
+	assert.EqualError(t, err, "channel mychannel doesn't exist")
+}
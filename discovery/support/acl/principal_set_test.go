@@ -0,0 +1,73 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package acl_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/discovery/support/acl"
+	"github.com/hyperledger/fabric/discovery/support/mocks"
+	"github.com/hyperledger/fabric/protos/msp"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSatisfiesPrincipalSet(t *testing.T) {
+	chConfig := &mocks.ChanConfig{}
+	resources := &mocks.Resources{}
+	mgr := &mocks.MSPManager{}
+	id := &mocks.Identity{}
+	chConfig.GetChannelConfigReturns(resources)
+	resources.MSPManagerReturns(mgr)
+	mgr.DeserializeIdentityReturns(id, nil)
+	id.SatisfiesPrincipalReturnsOnCall(0, nil)
+	id.SatisfiesPrincipalReturnsOnCall(1, errors.New("does not satisfy principal"))
+
+	sup := acl.NewDiscoverySupport(&mocks.Verifier{}, &mocks.Evaluator{}, chConfig)
+	principals := []*msp.MSPPrincipal{{}, {}}
+	errs := sup.SatisfiesPrincipalSet("mychannel", nil, principals)
+
+	assert.Len(t, errs, 2)
+	assert.NoError(t, errs[0])
+	assert.EqualError(t, errs[1], "does not satisfy principal")
+	// The identity should only have been deserialized once for the whole set.
+	assert.Equal(t, 1, mgr.DeserializeIdentityCallCount())
+}
+
+func TestSatisfiesPrincipalSetForIdentities(t *testing.T) {
+	chConfig := &mocks.ChanConfig{}
+	resources := &mocks.Resources{}
+	mgr := &mocks.MSPManager{}
+	id1 := &mocks.Identity{}
+	id2 := &mocks.Identity{}
+	chConfig.GetChannelConfigReturns(resources)
+	resources.MSPManagerReturns(mgr)
+	mgr.DeserializeIdentityReturnsOnCall(0, id1, nil)
+	mgr.DeserializeIdentityReturnsOnCall(1, id2, nil)
+	id1.SatisfiesPrincipalReturns(nil)
+	id2.SatisfiesPrincipalReturns(errors.New("does not satisfy principal"))
+
+	sup := acl.NewDiscoverySupport(&mocks.Verifier{}, &mocks.Evaluator{}, chConfig)
+	principals := []*msp.MSPPrincipal{{}}
+	errs := sup.SatisfiesPrincipalSetForIdentities("mychannel", [][]byte{[]byte("id1"), []byte("id2")}, principals)
+
+	assert.Len(t, errs, 2)
+	assert.NoError(t, errs[0][0])
+	assert.EqualError(t, errs[1][0], "does not satisfy principal")
+}
+
+func TestSatisfiesPrincipalSetChannelDoesNotExist(t *testing.T) {
+	chConfig := &mocks.ChanConfig{}
+	chConfig.GetChannelConfigReturns(nil)
+
+	sup := acl.NewDiscoverySupport(&mocks.Verifier{}, &mocks.Evaluator{}, chConfig)
+	errs := sup.SatisfiesPrincipalSet("mychannel", nil, []*msp.MSPPrincipal{{}, {}})
+
+	assert.Len(t, errs, 2)
+	assert.EqualError(t, errs[0], "channel mychannel doesn't exist")
+	assert.EqualError(t, errs[1], "channel mychannel doesn't exist")
+}
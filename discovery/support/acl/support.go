@@ -0,0 +1,217 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package acl
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/msp"
+	"github.com/pkg/errors"
+)
+
+// Verifier verifies a signature and a message
+type Verifier interface {
+	// VerifyByChannel checks that signature is a valid signature of message
+	// under a peer's verification key, but also in the context of a specific channel.
+	// If the verification succeeded, Verify returns nil meaning no error occurred.
+	// If peerIdentity is nil, then the verification fails.
+	VerifyByChannel(chainID string, peerIdentity []byte, signature, message []byte) error
+}
+
+// Evaluator evaluates signatures.
+// It is used to evaluate local MSP signatures.
+type Evaluator interface {
+	// Evaluate takes a set of signed data and evaluates whether this set of signed data
+	// satisfies the policy
+	Evaluate(signatureSet []*common.SignedData) error
+}
+
+// ChannelConfigGetter enables to retrieve the channel config resources for a given channel
+type ChannelConfigGetter interface {
+	// GetChannelConfig returns the resources of the channel config
+	GetChannelConfig(cid string) channelconfig.Resources
+}
+
+// ChannelConfigGetterFunc is a function adapter for ChannelConfigGetter
+type ChannelConfigGetterFunc func(cid string) channelconfig.Resources
+
+// GetChannelConfig returns the resources of the channel config
+func (f ChannelConfigGetterFunc) GetChannelConfig(cid string) channelconfig.Resources {
+	return f(cid)
+}
+
+// DiscoverySupport implements support that is used for service discovery
+// that is related to access control
+type DiscoverySupport struct {
+	Verifier
+	Evaluator
+	ChannelConfigGetter
+
+	ordererConfigSource OrdererConfigSource
+
+	// resolveConfig is the single point through which every method below
+	// resolves a channel's channelconfig.Resources. It defaults to
+	// defaultResolveConfig, but CachingDiscoverySupport replaces it with a
+	// caching variant so that ConfigSequence, SatisfiesPrincipal and the
+	// batched SatisfiesPrincipalSet/SatisfiesPrincipalSetForIdentities all
+	// benefit from the cache uniformly, instead of each needing its own
+	// cache-aware override.
+	resolveConfig func(channel string) channelconfig.Resources
+}
+
+// NewDiscoverySupport creates a new DiscoverySupport
+func NewDiscoverySupport(v Verifier, e Evaluator, s ChannelConfigGetter, opts ...Option) *DiscoverySupport {
+	sup := &DiscoverySupport{Verifier: v, Evaluator: e, ChannelConfigGetter: s}
+	sup.resolveConfig = sup.defaultResolveConfig
+	for _, opt := range opts {
+		opt(sup)
+	}
+	return sup
+}
+
+// defaultResolveConfig resolves a channel's configuration by asking the
+// configured ChannelConfigGetter, falling back to the configured
+// OrdererConfigSource, if any, when the channel is unknown locally.
+func (s *DiscoverySupport) defaultResolveConfig(channel string) channelconfig.Resources {
+	chConf := s.ChannelConfigGetter.GetChannelConfig(channel)
+	if chConf == nil {
+		chConf = s.channelConfigFromOrderer(channel)
+	}
+	return chConf
+}
+
+// EligibleForService checks that the given peer identity satisfies the eligibility
+// criteria for some channel.
+// In case it is eligible, nil is returned. Otherwise, a contextualized error
+// is returned.
+func (s *DiscoverySupport) EligibleForService(channel string, data common.SignedData) error {
+	if channel == "" {
+		return s.Evaluate([]*common.SignedData{&data})
+	}
+	return s.VerifyByChannel(channel, data.Identity, data.Signature, data.Data)
+}
+
+// ConfigSequence returns the configuration sequence of the given channel
+func (s *DiscoverySupport) ConfigSequence(channel string) uint64 {
+	if channel == "" {
+		// No channel, hence we're in the context of system wide queries,
+		// such as listing all channels
+		return 0
+	}
+	chConf := s.resolveConfig(channel)
+	if chConf == nil {
+		panic(fmt.Sprintf("could not find resources for channel %s", channel))
+	}
+	validator := chConf.ConfigtxValidator()
+	if validator == nil {
+		panic(fmt.Sprintf("could not find configtx validator for channel %s", channel))
+	}
+	return validator.Sequence()
+}
+
+// MSPOfPrincipal returns the MSP ID that the given principal is classified under,
+// or an empty string if the principal's classification is unsupported or its
+// bytes cannot be unmarshaled.
+func (s *DiscoverySupport) MSPOfPrincipal(principal *msp.MSPPrincipal) string {
+	if principal == nil {
+		return ""
+	}
+	switch principal.PrincipalClassification {
+	case msp.MSPPrincipal_ROLE:
+		mspRole := &msp.MSPRole{}
+		if err := proto.Unmarshal(principal.Principal, mspRole); err != nil {
+			return ""
+		}
+		return mspRole.MspIdentifier
+	case msp.MSPPrincipal_ORGANIZATION_UNIT:
+		ou := &msp.OrganizationUnit{}
+		if err := proto.Unmarshal(principal.Principal, ou); err != nil {
+			return ""
+		}
+		return ou.MspIdentifier
+	case msp.MSPPrincipal_IDENTITY:
+		sID := &msp.SerializedIdentity{}
+		if err := proto.Unmarshal(principal.Principal, sID); err != nil {
+			return ""
+		}
+		return sID.Mspid
+	default:
+		return ""
+	}
+}
+
+// SatisfiesPrincipal returns nil if the given rawIdentity satisfies the given principal
+// in the context of the given channel, or an error on failure or mismatch.
+// It is a thin wrapper around SatisfiesPrincipalSet for callers that only
+// need to check a single principal.
+func (s *DiscoverySupport) SatisfiesPrincipal(channel string, rawIdentity []byte, principal *msp.MSPPrincipal) error {
+	return s.SatisfiesPrincipalSet(channel, rawIdentity, []*msp.MSPPrincipal{principal})[0]
+}
+
+// SatisfiesPrincipalSet deserializes rawIdentity once and checks it against
+// each of the given principals in the context of the given channel,
+// returning a per-principal error slice. This amortizes the cost of
+// deserializing the identity and looking up the MSP manager across the
+// whole principal slice, instead of paying it once per principal the way
+// repeated calls to SatisfiesPrincipal would.
+func (s *DiscoverySupport) SatisfiesPrincipalSet(channel string, rawIdentity []byte, principals []*msp.MSPPrincipal) []error {
+	return s.SatisfiesPrincipalSetForIdentities(channel, [][]byte{rawIdentity}, principals)[0]
+}
+
+// SatisfiesPrincipalSetForIdentities is the plural-identities variant of
+// SatisfiesPrincipalSet: it deserializes each of rawIdentities once and
+// checks it against every principal in the context of the given channel,
+// returning, per identity, a per-principal error slice. Callers computing
+// an endorsement layout's satisfaction matrix over many peer identities and
+// many policy principals pay O(identities + principals) deserializations
+// this way, rather than O(identities × principals).
+func (s *DiscoverySupport) SatisfiesPrincipalSetForIdentities(channel string, rawIdentities [][]byte, principals []*msp.MSPPrincipal) [][]error {
+	chConf := s.resolveConfig(channel)
+	if chConf == nil {
+		return errorMatrix(len(rawIdentities), len(principals), errors.Errorf("channel %s doesn't exist", channel))
+	}
+	mspManager := chConf.MSPManager()
+	if mspManager == nil {
+		return errorMatrix(len(rawIdentities), len(principals), errors.Errorf("could not find MSP manager for channel %s", channel))
+	}
+
+	errs := make([][]error, len(rawIdentities))
+	for i, rawIdentity := range rawIdentities {
+		identity, err := mspManager.DeserializeIdentity(rawIdentity)
+		if err != nil {
+			errs[i] = errorSlice(len(principals), errors.Wrap(err, "failed deserializing identity"))
+			continue
+		}
+		perPrincipal := make([]error, len(principals))
+		for j, principal := range principals {
+			perPrincipal[j] = identity.SatisfiesPrincipal(principal)
+		}
+		errs[i] = perPrincipal
+	}
+	return errs
+}
+
+// errorSlice returns a slice of n copies of err.
+func errorSlice(n int, err error) []error {
+	errs := make([]error, n)
+	for i := range errs {
+		errs[i] = err
+	}
+	return errs
+}
+
+// errorMatrix returns a rows x cols matrix where every cell is err.
+func errorMatrix(rows, cols int, err error) [][]error {
+	errs := make([][]error, rows)
+	for i := range errs {
+		errs[i] = errorSlice(cols, err)
+	}
+	return errs
+}